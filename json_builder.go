@@ -0,0 +1,260 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonBuilderState tracks what kind of container a JSONBuilder is currently
+// writing into, and what it expects next.
+type jsonBuilderState int
+
+const (
+	jsonBuilderRoot jsonBuilderState = iota
+	jsonBuilderObjectKey
+	jsonBuilderObjectValue
+	jsonBuilderArrayStart
+	jsonBuilderArrayNext
+)
+
+// JSONBuilder incrementally assembles a safehtml.JSON value, rejecting
+// structurally invalid sequences (e.g. a value emitted where a key is
+// expected, or emission after the root value has closed) instead of
+// silently producing malformed JSON, as JSONConcat would.
+//
+// The zero value is ready to use.
+type JSONBuilder struct {
+	buf    bytes.Buffer
+	stack  []jsonBuilderState
+	keys   []map[string]bool // duplicate-key tracking per open object, nil unless strict is set
+	done   bool
+	err    error
+	strict bool
+}
+
+// NewJSONBuilder returns a JSONBuilder. If strictKeys is true, Key rejects a
+// duplicate key within the same object.
+func NewJSONBuilder(strictKeys bool) *JSONBuilder {
+	return &JSONBuilder{strict: strictKeys}
+}
+
+func (b *JSONBuilder) fail(format string, args ...any) {
+	if b.err == nil {
+		b.err = fmt.Errorf("safehtml: JSONBuilder: "+format, args...)
+	}
+}
+
+func (b *JSONBuilder) top() jsonBuilderState {
+	if len(b.stack) == 0 {
+		return jsonBuilderRoot
+	}
+	return b.stack[len(b.stack)-1]
+}
+
+// enterValue writes whatever separator is needed before a value (or the
+// opening token of a container) in the current position, and reports
+// whether emission is allowed. It must be called exactly once per emitted
+// value or BeginObject/BeginArray; Key writes its own separator.
+func (b *JSONBuilder) enterValue() bool {
+	if b.err != nil {
+		return false
+	}
+	switch b.top() {
+	case jsonBuilderRoot:
+		if b.done {
+			b.fail("value emitted after the root value was closed")
+			return false
+		}
+	case jsonBuilderObjectKey:
+		b.fail("expected a Key, got a value")
+		return false
+	case jsonBuilderObjectValue:
+		b.buf.WriteByte(':')
+		b.stack[len(b.stack)-1] = jsonBuilderObjectKey
+	case jsonBuilderArrayStart:
+		b.stack[len(b.stack)-1] = jsonBuilderArrayNext
+	case jsonBuilderArrayNext:
+		b.buf.WriteByte(',')
+	}
+	return true
+}
+
+// leaveValue marks the root value closed once the outermost container (or a
+// scalar root value) has finished.
+func (b *JSONBuilder) leaveValue() {
+	if len(b.stack) == 0 {
+		b.done = true
+	}
+}
+
+// BeginObject opens a JSON object.
+func (b *JSONBuilder) BeginObject() *JSONBuilder {
+	if !b.enterValue() {
+		return b
+	}
+	b.buf.WriteByte('{')
+	b.stack = append(b.stack, jsonBuilderObjectKey)
+	if b.strict {
+		b.keys = append(b.keys, map[string]bool{})
+	}
+	return b
+}
+
+// EndObject closes the most recently opened JSON object.
+func (b *JSONBuilder) EndObject() *JSONBuilder {
+	if b.err != nil {
+		return b
+	}
+	if b.top() != jsonBuilderObjectKey {
+		b.fail("EndObject called without a matching BeginObject")
+		return b
+	}
+	b.buf.WriteByte('}')
+	b.stack = b.stack[:len(b.stack)-1]
+	if b.strict {
+		b.keys = b.keys[:len(b.keys)-1]
+	}
+	b.leaveValue()
+	return b
+}
+
+// BeginArray opens a JSON array.
+func (b *JSONBuilder) BeginArray() *JSONBuilder {
+	if !b.enterValue() {
+		return b
+	}
+	b.buf.WriteByte('[')
+	b.stack = append(b.stack, jsonBuilderArrayStart)
+	return b
+}
+
+// EndArray closes the most recently opened JSON array.
+func (b *JSONBuilder) EndArray() *JSONBuilder {
+	if b.err != nil {
+		return b
+	}
+	switch b.top() {
+	case jsonBuilderArrayStart, jsonBuilderArrayNext:
+	default:
+		b.fail("EndArray called without a matching BeginArray")
+		return b
+	}
+	b.buf.WriteByte(']')
+	b.stack = b.stack[:len(b.stack)-1]
+	b.leaveValue()
+	return b
+}
+
+// Key emits an object key. It must be called while the builder is
+// expecting a key, i.e. directly after BeginObject or after a value within
+// that object.
+func (b *JSONBuilder) Key(key string) *JSONBuilder {
+	if b.err != nil {
+		return b
+	}
+	if b.top() != jsonBuilderObjectKey {
+		b.fail("Key called outside of an object, or while a value was expected")
+		return b
+	}
+	if b.strict {
+		seen := b.keys[len(b.keys)-1]
+		if seen[key] {
+			b.fail("duplicate key %q", key)
+			return b
+		}
+		seen[key] = true
+	}
+	if b.buf.Len() > 0 {
+		switch b.buf.Bytes()[b.buf.Len()-1] {
+		case '{':
+		default:
+			b.buf.WriteByte(',')
+		}
+	}
+	writeEscapedString(&b.buf, key, JSONEscapeOptions{EscapeHTML: true})
+	b.stack[len(b.stack)-1] = jsonBuilderObjectValue
+	return b
+}
+
+// StringValue emits s as a quoted JSON string, escaped as by
+// JSONFromValueWithOptions with JSONEscapeOptions{EscapeHTML: true}.
+func (b *JSONBuilder) StringValue(s string) *JSONBuilder {
+	if !b.enterValue() {
+		return b
+	}
+	writeEscapedString(&b.buf, s, JSONEscapeOptions{EscapeHTML: true})
+	b.leaveValue()
+	return b
+}
+
+// NumberValue emits a JSON number verbatim.
+func (b *JSONBuilder) NumberValue(n json.Number) *JSONBuilder {
+	if !b.enterValue() {
+		return b
+	}
+	b.buf.WriteString(string(n))
+	b.leaveValue()
+	return b
+}
+
+// BoolValue emits a JSON boolean.
+func (b *JSONBuilder) BoolValue(v bool) *JSONBuilder {
+	if !b.enterValue() {
+		return b
+	}
+	if v {
+		b.buf.WriteString("true")
+	} else {
+		b.buf.WriteString("false")
+	}
+	b.leaveValue()
+	return b
+}
+
+// NullValue emits a JSON null.
+func (b *JSONBuilder) NullValue() *JSONBuilder {
+	if !b.enterValue() {
+		return b
+	}
+	b.buf.WriteString("null")
+	b.leaveValue()
+	return b
+}
+
+// RawValue emits the string form of j verbatim, as a single value.
+func (b *JSONBuilder) RawValue(j JSON) *JSONBuilder {
+	if !b.enterValue() {
+		return b
+	}
+	b.buf.WriteString(j.String())
+	b.leaveValue()
+	return b
+}
+
+// Build returns the completed JSON, or an error if the builder's call
+// sequence was structurally invalid or no value was ever emitted.
+func (b *JSONBuilder) Build() (JSON, error) {
+	if b.err != nil {
+		return JSON{}, b.err
+	}
+	if !b.done || len(b.stack) != 0 {
+		return JSON{}, fmt.Errorf("safehtml: JSONBuilder: Build called before the root value was closed")
+	}
+	return JSON{str: b.buf.String()}, nil
+}
+
+// MustBuild is like Build, but panics instead of returning an error.
+func (b *JSONBuilder) MustBuild() JSON {
+	j, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return j
+}
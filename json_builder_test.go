@@ -0,0 +1,98 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONBuilder_ObjectAndArray(t *testing.T) {
+	b := NewJSONBuilder(false)
+	b.BeginObject().
+		Key("name").StringValue("Ada").
+		Key("tags").BeginArray().StringValue("x").StringValue("y").EndArray().
+		Key("active").BoolValue(true).
+		Key("extra").NullValue().
+		EndObject()
+
+	out, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	want := `{"name":"Ada","tags":["x","y"],"active":true,"extra":null}`
+	if got := out.String(); got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
+
+func TestJSONBuilder_NumberAndRaw(t *testing.T) {
+	b := NewJSONBuilder(false)
+	b.BeginArray().
+		NumberValue(json.Number("42")).
+		RawValue(JSONFromConstant("true")).
+		EndArray()
+
+	out, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if got, want := out.String(), `[42,true]`; got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
+
+func TestJSONBuilder_KeyWithoutObject(t *testing.T) {
+	b := NewJSONBuilder(false)
+	b.Key("a")
+	if _, err := b.Build(); err == nil {
+		t.Error("Build() after Key outside of an object: want error, got nil")
+	}
+}
+
+func TestJSONBuilder_MismatchedEnd(t *testing.T) {
+	b := NewJSONBuilder(false)
+	b.BeginArray()
+	b.EndObject()
+	if _, err := b.Build(); err == nil {
+		t.Error("Build() after EndObject without matching BeginObject: want error, got nil")
+	}
+}
+
+func TestJSONBuilder_ValueAfterRootClosed(t *testing.T) {
+	b := NewJSONBuilder(false)
+	b.StringValue("a")
+	b.StringValue("b")
+	if _, err := b.Build(); err == nil {
+		t.Error("Build() after a second root value: want error, got nil")
+	}
+}
+
+func TestJSONBuilder_DuplicateKeyStrict(t *testing.T) {
+	b := NewJSONBuilder(true)
+	b.BeginObject().Key("a").StringValue("1").Key("a").StringValue("2").EndObject()
+	if _, err := b.Build(); err == nil {
+		t.Error("Build() with duplicate key in strict mode: want error, got nil")
+	}
+}
+
+func TestJSONBuilder_IncompleteBuild(t *testing.T) {
+	b := NewJSONBuilder(false)
+	b.BeginObject().Key("a").StringValue("1")
+	if _, err := b.Build(); err == nil {
+		t.Error("Build() before EndObject: want error, got nil")
+	}
+}
+
+func TestJSONBuilder_MustBuildPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustBuild() on invalid builder: want panic, got none")
+		}
+	}()
+	NewJSONBuilder(false).Key("a").MustBuild()
+}
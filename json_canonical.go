@@ -0,0 +1,196 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// JSONCanonical returns j re-serialized per RFC 8785, the JSON
+// Canonicalization Scheme (JCS): object keys sorted by their UTF-16
+// code-unit sequence, numbers formatted per the ECMAScript
+// Number.prototype.toString algorithm, strings escaped with the minimal
+// JSON escape set, and no insignificant whitespace. The result is suitable
+// as a stable cache key, signing input, or content-addressable identifier.
+func JSONCanonical(j JSON) (JSON, error) {
+	return JSONFromValueCanonical(j.String())
+}
+
+// JSONFromValueCanonical parses input as JSON and returns its RFC 8785
+// canonical form. See JSONCanonical.
+func JSONFromValueCanonical(input string) (out JSON, err error) {
+	dec := json.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+
+	var v any
+	if err = dec.Decode(&v); err != nil {
+		return out, err
+	}
+
+	var b strings.Builder
+	if err = encodeCanonical(&b, v); err != nil {
+		return out, err
+	}
+	return JSON{str: b.String()}, nil
+}
+
+func encodeCanonical(b *strings.Builder, v any) error {
+	switch t := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		if t {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+	case json.Number:
+		return encodeCanonicalNumber(b, t)
+	case string:
+		encodeCanonicalString(b, t)
+	case []any:
+		b.WriteByte('[')
+		for i, elem := range t {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := encodeCanonical(b, elem); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return utf16Less(keys[i], keys[j])
+		})
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			encodeCanonicalString(b, k)
+			b.WriteByte(':')
+			if err := encodeCanonical(b, t[k]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		return fmt.Errorf("safehtml: JSONCanonical: unsupported value of type %T", v)
+	}
+	return nil
+}
+
+// utf16Less reports whether a sorts before b when both are compared as
+// sequences of UTF-16 code units, as required by RFC 8785 section 3.2.3.
+func utf16Less(a, b string) bool {
+	au, bu := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(au) && i < len(bu); i++ {
+		if au[i] != bu[i] {
+			return au[i] < bu[i]
+		}
+	}
+	return len(au) < len(bu)
+}
+
+var canonicalShortEscapes = map[byte]string{
+	'"':  `\"`,
+	'\\': `\\`,
+	'\b': `\b`,
+	'\f': `\f`,
+	'\n': `\n`,
+	'\r': `\r`,
+	'\t': `\t`,
+}
+
+// encodeCanonicalString writes s as a JSON string using the minimal escape
+// set required by RFC 8785: '"', '\\', the named C0 control escapes, and
+// "\u00XX" for any other character below U+0020. All other characters,
+// including non-ASCII ones, are copied through verbatim.
+func encodeCanonicalString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if esc, ok := canonicalShortEscapes[c]; ok {
+			b.WriteString(esc)
+			continue
+		}
+		if c < 0x20 {
+			const hex = "0123456789abcdef"
+			b.WriteString(`\u00`)
+			b.WriteByte(hex[(c>>4)&0xf])
+			b.WriteByte(hex[c&0xf])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+}
+
+// encodeCanonicalNumber formats n per the ECMAScript Number.prototype.
+// toString algorithm: fixed-point notation is used except when the
+// magnitude is >= 1e21 or < 1e-6, matching the thresholds at which
+// ECMAScript itself switches to exponential notation; the exponent's
+// leading zeros are stripped to match ECMAScript's output. Negative zero
+// is normalized to "0", as ECMAScript's algorithm requires.
+func encodeCanonicalNumber(b *strings.Builder, n json.Number) error {
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return fmt.Errorf("safehtml: JSONCanonical: %w", err)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("safehtml: JSONCanonical: %v is not valid JSON", f)
+	}
+
+	if f == 0 {
+		b.WriteString("0")
+		return nil
+	}
+
+	if mag := math.Abs(f); mag >= 1e21 || mag < 1e-6 {
+		s := strconv.FormatFloat(f, 'e', -1, 64)
+		b.WriteString(normalizeExponent(s))
+		return nil
+	}
+
+	b.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+	return nil
+}
+
+// normalizeExponent rewrites the exponent of a strconv.FormatFloat 'e'-form
+// number (e.g. "1.5e+05") to ECMAScript style, which has no leading zeros
+// in the exponent (e.g. "1.5e+5").
+func normalizeExponent(s string) string {
+	i := strings.IndexByte(s, 'e')
+	if i < 0 {
+		return s
+	}
+	mantissa, exp := s[:i], s[i+1:]
+
+	sign := "+"
+	if len(exp) > 0 && (exp[0] == '+' || exp[0] == '-') {
+		if exp[0] == '-' {
+			sign = "-"
+		}
+		exp = exp[1:]
+	}
+	exp = strings.TrimLeft(exp, "0")
+	if exp == "" {
+		exp = "0"
+	}
+	return mantissa + "e" + sign + exp
+}
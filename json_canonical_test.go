@@ -0,0 +1,74 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import "testing"
+
+func TestJSONFromValueCanonical_Numbers(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"0.5", "0.5"},
+		{"19.99", "19.99"},
+		{"100.5", "100.5"},
+		{"3.14159", "3.14159"},
+		{"-0", "0"},
+		{"-0.0", "0"},
+		{"5", "5"},
+		{"5.0", "5"},
+		{"1e21", "1e+21"},
+		{"1e-7", "1e-7"},
+		{"0.000001", "0.000001"},
+	}
+	for _, tc := range tests {
+		out, err := JSONFromValueCanonical(tc.input)
+		if err != nil {
+			t.Errorf("JSONFromValueCanonical(%q): %v", tc.input, err)
+			continue
+		}
+		if got := out.String(); got != tc.want {
+			t.Errorf("JSONFromValueCanonical(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestJSONFromValueCanonical_SortsKeys(t *testing.T) {
+	out, err := JSONFromValueCanonical(`{"b":1,"a":2,"c":3}`)
+	if err != nil {
+		t.Fatalf("JSONFromValueCanonical: %v", err)
+	}
+	if got, want := out.String(), `{"a":2,"b":1,"c":3}`; got != want {
+		t.Errorf("JSONFromValueCanonical = %q, want %q", got, want)
+	}
+}
+
+func TestJSONFromValueCanonical_MinimalStringEscapes(t *testing.T) {
+	out, err := JSONFromValueCanonical(`"café <tag> & \"quote\""`)
+	if err != nil {
+		t.Fatalf("JSONFromValueCanonical: %v", err)
+	}
+	// Non-ASCII and HTML-special characters must pass through unescaped;
+	// only '"' requires an escape here.
+	if got, want := out.String(), `"café <tag> & \"quote\""`; got != want {
+		t.Errorf("JSONFromValueCanonical = %q, want %q", got, want)
+	}
+}
+
+func TestJSONCanonical(t *testing.T) {
+	j, err := JSONFromValue(`{"b":1,"a":2}`)
+	if err != nil {
+		t.Fatalf("JSONFromValue: %v", err)
+	}
+	out, err := JSONCanonical(j)
+	if err != nil {
+		t.Fatalf("JSONCanonical: %v", err)
+	}
+	if got, want := out.String(), `{"a":2,"b":1}`; got != want {
+		t.Errorf("JSONCanonical = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// jsonEncoderPool recycles the bytes.Buffers used by JSONFromMarshaler and
+// JSONFromMarshalerIndent, avoiding a buffer allocation per call on hot
+// paths. A json.Encoder is still created for each call.
+var jsonEncoderPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// JSONFromMarshaler returns the JSON produced by marshaling v, HTML-escaped
+// per the guarantee made by the JSON type. Unlike calling json.Marshal and
+// passing the result to JSONFromValue, v is encoded directly without being
+// re-parsed.
+func JSONFromMarshaler(v any) (JSON, error) {
+	return jsonFromMarshaler(v, "", "")
+}
+
+// JSONFromMarshalerIndent is like JSONFromMarshaler, but the output is
+// indented per json.Encoder.SetIndent(prefix, indent).
+func JSONFromMarshalerIndent(v any, prefix, indent string) (JSON, error) {
+	return jsonFromMarshaler(v, prefix, indent)
+}
+
+func jsonFromMarshaler(v any, prefix, indent string) (out JSON, err error) {
+	buf := jsonEncoderPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer jsonEncoderPool.Put(buf)
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(true)
+	if indent != "" || prefix != "" {
+		enc.SetIndent(prefix, indent)
+	}
+	if err = enc.Encode(v); err != nil {
+		return out, err
+	}
+	// json.Encoder.Encode always appends a trailing newline; strip it so
+	// the JSON value contains exactly the encoded document.
+	return JSON{str: string(bytes.TrimRight(buf.Bytes(), "\n"))}, nil
+}
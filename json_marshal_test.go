@@ -0,0 +1,40 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONFromMarshaler(t *testing.T) {
+	type payload struct {
+		A string `json:"a"`
+		B int    `json:"b"`
+	}
+
+	out, err := JSONFromMarshaler(payload{A: "<script>", B: 7})
+	if err != nil {
+		t.Fatalf("JSONFromMarshaler: %v", err)
+	}
+	if strings.Contains(out.String(), "<script>") {
+		t.Errorf("JSONFromMarshaler should HTML-escape by default, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), `"b":7`) {
+		t.Errorf("JSONFromMarshaler output %q missing expected field", out.String())
+	}
+}
+
+func TestJSONFromMarshalerIndent(t *testing.T) {
+	out, err := JSONFromMarshalerIndent(map[string]int{"a": 1}, "", "  ")
+	if err != nil {
+		t.Fatalf("JSONFromMarshalerIndent: %v", err)
+	}
+	if !strings.Contains(out.String(), "\n") {
+		t.Errorf("JSONFromMarshalerIndent should indent with newlines, got %q", out.String())
+	}
+}
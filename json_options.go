@@ -0,0 +1,292 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// BackslashEscapeMode controls how characters that require a backslash
+// escape are rendered by JSONFromValueWithOptions and JSONFromReader.
+type BackslashEscapeMode int
+
+const (
+	// BackslashEscapeShort renders an escapable character using its short
+	// form (e.g. "\n", "\t") when one exists, falling back to "\uXXXX"
+	// otherwise. This is the default mode.
+	BackslashEscapeShort BackslashEscapeMode = iota
+	// BackslashEscapeUnicode always renders an escapable character as
+	// "\uXXXX", even when a short form exists.
+	BackslashEscapeUnicode
+	// BackslashEscapePreserve emits the character as-is, without any
+	// backslash escaping. It has no effect on the characters that JSON
+	// requires to be escaped (`"`, `\`, and control characters below
+	// U+0020), nor, when EscapeHTML is set, on '<', '>', '&', U+2028 and
+	// U+2029; those are always escaped regardless of mode.
+	BackslashEscapePreserve
+)
+
+// BackslashEscaper lets a caller override the BackslashEscapeMode used for a
+// particular rune. was is the mode that would otherwise be used; the
+// returned mode is used instead.
+type BackslashEscaper func(r rune, was BackslashEscapeMode) BackslashEscapeMode
+
+// JSONEscapeOptions controls how JSONFromValueWithOptions and JSONFromReader
+// escape their output.
+type JSONEscapeOptions struct {
+	// EscapeHTML, if true, escapes '<', '>', '&', U+2028 and U+2029 so the
+	// resulting JSON is safe to embed verbatim inside HTML and <script>
+	// contexts. If false, the output is only safe for use as a
+	// Content-Type: application/json body.
+	EscapeHTML bool
+
+	// EscapeUnicode, if true, escapes all non-ASCII runes as "\uXXXX"
+	// (a UTF-16 surrogate pair for runes above U+FFFF). If false, valid
+	// UTF-8 is passed through unescaped.
+	EscapeUnicode bool
+
+	// BackslashEscapeMode selects how escapable characters are rendered.
+	BackslashEscapeMode BackslashEscapeMode
+
+	// BackslashEscaper, if non-nil, is consulted for every escapable rune
+	// and may override BackslashEscapeMode on a per-rune basis.
+	BackslashEscaper BackslashEscaper
+}
+
+// safeSet holds, for every ASCII byte, whether it may be copied to JSON
+// output verbatim without any escaping when HTML-escaping is disabled.
+var safeSet [256]bool
+
+func init() {
+	for b := 0x20; b <= 0x7e; b++ {
+		safeSet[b] = true
+	}
+	safeSet['"'] = false
+	safeSet['\\'] = false
+}
+
+// JSONFromValueWithOptions is like JSONFromValue, but gives the caller
+// control over escaping via opts. Unlike JSONFromValue, the input is
+// tokenized with a json.Decoder (preserving object key order and, via
+// UseNumber, numeric fidelity) and re-emitted with a hand-written escaper
+// instead of round-tripping through json.Unmarshal/json.Marshal.
+//
+// If opts.EscapeHTML is true, the resulting JSON is safe to embed verbatim
+// in HTML and <script> contexts. If false, it is safe only for use as a
+// Content-Type: application/json body.
+func JSONFromValueWithOptions(input string, opts JSONEscapeOptions) (JSON, error) {
+	return jsonFromReaderWithOptions(strings.NewReader(input), opts)
+}
+
+// JSONFromReader is like JSONFromValue, but streams the input through a
+// json.Decoder instead of buffering it in full before parsing. It defaults
+// to HTML-safe escaping, matching the guarantee made by JSONFromValue.
+func JSONFromReader(r io.Reader) (out JSON, err error) {
+	return jsonFromReaderWithOptions(r, JSONEscapeOptions{EscapeHTML: true})
+}
+
+func jsonFromReaderWithOptions(r io.Reader, opts JSONEscapeOptions) (out JSON, err error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	if err = reEncodeValue(dec, &buf, opts); err != nil {
+		return out, err
+	}
+	// json.Decoder stops after the first value; reject anything left over
+	// so a nil error always means the whole input was valid JSON, matching
+	// JSONFromValue's behavior.
+	if _, err = dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("safehtml: JSONFromValueWithOptions: invalid character after top-level value")
+		}
+		return out, err
+	}
+	return JSON{str: buf.String()}, nil
+}
+
+// reEncodeValue consumes exactly one JSON value (scalar, object, or array)
+// from dec and writes its re-escaped form to buf.
+func reEncodeValue(dec *json.Decoder, buf *bytes.Buffer, opts JSONEscapeOptions) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	return reEncodeToken(tok, dec, buf, opts)
+}
+
+func reEncodeToken(tok json.Token, dec *json.Decoder, buf *bytes.Buffer, opts JSONEscapeOptions) error {
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case json.Delim('{'):
+			return reEncodeObject(dec, buf, opts)
+		case json.Delim('['):
+			return reEncodeArray(dec, buf, opts)
+		}
+		return nil
+	case string:
+		writeEscapedString(buf, v, opts)
+		return nil
+	case json.Number:
+		buf.WriteString(string(v))
+		return nil
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+		return nil
+	case nil:
+		buf.WriteString("null")
+		return nil
+	default:
+		return nil
+	}
+}
+
+func reEncodeObject(dec *json.Decoder, buf *bytes.Buffer, opts JSONEscapeOptions) error {
+	buf.WriteByte('{')
+	first := true
+	for dec.More() {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		keyTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := keyTok.(string)
+		writeEscapedString(buf, key, opts)
+		buf.WriteByte(':')
+
+		if err := reEncodeValue(dec, buf, opts); err != nil {
+			return err
+		}
+	}
+	// Consume the closing '}'.
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func reEncodeArray(dec *json.Decoder, buf *bytes.Buffer, opts JSONEscapeOptions) error {
+	buf.WriteByte('[')
+	first := true
+	for dec.More() {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		if err := reEncodeValue(dec, buf, opts); err != nil {
+			return err
+		}
+	}
+	// Consume the closing ']'.
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// writeEscapedString writes s to buf as a double-quoted JSON string,
+// escaped according to opts.
+func writeEscapedString(buf *bytes.Buffer, s string, opts JSONEscapeOptions) {
+	s = coerceToUTF8InterchangeValid(s)
+
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r < 0x80 && safeSet[r] && !(opts.EscapeHTML && (r == '<' || r == '>' || r == '&')):
+			buf.WriteByte(byte(r))
+		case r == '"' || r == '\\' || r < 0x20:
+			writeBackslashEscape(buf, r, opts)
+		case r == ' ' || r == ' ':
+			if opts.EscapeHTML || opts.EscapeUnicode {
+				writeBackslashEscape(buf, r, opts)
+			} else {
+				buf.WriteRune(r)
+			}
+		case r == '<' || r == '>' || r == '&':
+			writeBackslashEscape(buf, r, opts)
+		case r >= 0x80 && opts.EscapeUnicode:
+			writeBackslashEscape(buf, r, opts)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+var shortBackslashEscapes = map[rune]string{
+	'"':  `\"`,
+	'\\': `\\`,
+	'\b': `\b`,
+	'\f': `\f`,
+	'\n': `\n`,
+	'\r': `\r`,
+	'\t': `\t`,
+}
+
+func writeBackslashEscape(buf *bytes.Buffer, r rune, opts JSONEscapeOptions) {
+	mode := opts.BackslashEscapeMode
+	if opts.BackslashEscaper != nil {
+		mode = opts.BackslashEscaper(r, mode)
+	}
+
+	// '"', '\\' and control characters must always be escaped somehow, and
+	// so must '<', '>', '&', U+2028 and U+2029 when EscapeHTML is set;
+	// BackslashEscapePreserve only applies to the remaining optional escapes.
+	mustEscape := r == '"' || r == '\\' || r < 0x20 ||
+		(opts.EscapeHTML && (r == '<' || r == '>' || r == '&' || r == ' ' || r == ' '))
+	if mode == BackslashEscapePreserve && !mustEscape {
+		buf.WriteRune(r)
+		return
+	}
+
+	if mode != BackslashEscapeUnicode {
+		if short, ok := shortBackslashEscapes[r]; ok {
+			buf.WriteString(short)
+			return
+		}
+	}
+
+	if r > 0xffff {
+		// Encode as a UTF-16 surrogate pair.
+		r1, r2 := utf16Encode(r)
+		writeUnicodeEscape(buf, r1)
+		writeUnicodeEscape(buf, r2)
+		return
+	}
+	writeUnicodeEscape(buf, r)
+}
+
+func writeUnicodeEscape(buf *bytes.Buffer, r rune) {
+	const hex = "0123456789abcdef"
+	buf.WriteString(`\u`)
+	buf.WriteByte(hex[(r>>12)&0xf])
+	buf.WriteByte(hex[(r>>8)&0xf])
+	buf.WriteByte(hex[(r>>4)&0xf])
+	buf.WriteByte(hex[r&0xf])
+}
+
+func utf16Encode(r rune) (r1, r2 rune) {
+	const (
+		surr1    = 0xd800
+		surr2    = 0xdc00
+		surrSelf = 0x10000
+	)
+	r -= surrSelf
+	return surr1 + (r>>10)&0x3ff, surr2 + r&0x3ff
+}
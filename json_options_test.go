@@ -0,0 +1,87 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONFromValueWithOptions_HTMLEscaping(t *testing.T) {
+	const input = `{"a":"<script>alert(1)</script>"}`
+
+	htmlSafe, err := JSONFromValueWithOptions(input, JSONEscapeOptions{EscapeHTML: true})
+	if err != nil {
+		t.Fatalf("JSONFromValueWithOptions(EscapeHTML=true): %v", err)
+	}
+	if strings.ContainsAny(htmlSafe.String(), "<>") {
+		t.Errorf("EscapeHTML=true output contains raw '<' or '>': %q", htmlSafe.String())
+	}
+
+	wireSafe, err := JSONFromValueWithOptions(input, JSONEscapeOptions{EscapeHTML: false})
+	if err != nil {
+		t.Fatalf("JSONFromValueWithOptions(EscapeHTML=false): %v", err)
+	}
+	if !strings.Contains(wireSafe.String(), "<script>") {
+		t.Errorf("EscapeHTML=false output should pass '<' and '>' through unescaped, got %q", wireSafe.String())
+	}
+}
+
+func TestJSONFromValueWithOptions_PreservesKeyOrder(t *testing.T) {
+	const input = `{"b":1,"a":2}`
+
+	out, err := JSONFromValueWithOptions(input, JSONEscapeOptions{})
+	if err != nil {
+		t.Fatalf("JSONFromValueWithOptions: %v", err)
+	}
+	if got, want := out.String(), `{"b":1,"a":2}`; got != want {
+		t.Errorf("JSONFromValueWithOptions(%q) = %q, want %q (key order preserved)", input, got, want)
+	}
+}
+
+func TestJSONFromValueWithOptions_RejectsTrailingGarbage(t *testing.T) {
+	tests := []string{
+		`5 garbage`,
+		`{"a":1} trailing junk`,
+		`[1,2] ]`,
+	}
+	for _, input := range tests {
+		if _, err := JSONFromValueWithOptions(input, JSONEscapeOptions{}); err == nil {
+			t.Errorf("JSONFromValueWithOptions(%q) succeeded, want error for trailing data", input)
+		}
+	}
+}
+
+func TestJSONFromReader(t *testing.T) {
+	out, err := JSONFromReader(strings.NewReader(`{"a":"<b>"}`))
+	if err != nil {
+		t.Fatalf("JSONFromReader: %v", err)
+	}
+	if strings.Contains(out.String(), "<b>") {
+		t.Errorf("JSONFromReader should default to HTML-safe escaping, got %q", out.String())
+	}
+}
+
+func TestJSONFromValueWithOptions_BackslashEscapeModes(t *testing.T) {
+	const input = `{"a":"line\nbreak"}`
+
+	short, err := JSONFromValueWithOptions(input, JSONEscapeOptions{BackslashEscapeMode: BackslashEscapeShort})
+	if err != nil {
+		t.Fatalf("JSONFromValueWithOptions(short): %v", err)
+	}
+	if !strings.Contains(short.String(), `\n`) {
+		t.Errorf("BackslashEscapeShort: want %q to contain %q", short.String(), `\n`)
+	}
+
+	unicode, err := JSONFromValueWithOptions(input, JSONEscapeOptions{BackslashEscapeMode: BackslashEscapeUnicode})
+	if err != nil {
+		t.Fatalf("JSONFromValueWithOptions(unicode): %v", err)
+	}
+	if !strings.Contains(unicode.String(), "\\u000a") {
+		t.Errorf("BackslashEscapeUnicode: want %q to contain %q", unicode.String(), "\\u000a")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONSanitizePolicy controls how JSONFromValuePolicy handles invalid UTF-8
+// and non-printable ASCII control characters in its input, before the
+// input is parsed as JSON.
+type JSONSanitizePolicy int
+
+const (
+	// PolicyStrict performs no sanitization; malformed input is rejected
+	// by json.Unmarshal exactly as JSONFromValue would reject it.
+	PolicyStrict JSONSanitizePolicy = iota
+	// PolicyReplaceInvalid replaces invalid UTF-8 byte sequences with
+	// U+FFFD before parsing.
+	PolicyReplaceInvalid
+	// PolicyStripControl removes bytes below U+0020, other than tab,
+	// newline, and carriage return, before parsing.
+	PolicyStripControl
+	// PolicyEscapeControl rewrites bytes below U+0020, other than tab,
+	// newline, and carriage return, as "\u00XX" before parsing.
+	PolicyEscapeControl
+)
+
+// JSONFromValuePolicy is like JSONFromValue, but applies p to input before
+// parsing, so that malformed real-world input (stray control characters,
+// invalid UTF-8) yields a usable, deterministically sanitized JSON instead
+// of an error.
+func JSONFromValuePolicy(input string, p JSONSanitizePolicy) (out JSON, err error) {
+	sanitized := sanitizeJSONInput(input, p)
+
+	var x any
+	var j []byte
+	if err = json.Unmarshal([]byte(sanitized), &x); err != nil {
+		return out, err
+	}
+	if j, err = json.Marshal(x); err != nil {
+		return out, err
+	}
+	return JSON{str: string(j)}, nil
+}
+
+// sanitizeJSONInput applies p to input.
+func sanitizeJSONInput(input string, p JSONSanitizePolicy) string {
+	switch p {
+	case PolicyReplaceInvalid:
+		return replaceInvalidUTF8(input)
+	case PolicyStripControl:
+		return filterControl(input, false)
+	case PolicyEscapeControl:
+		return filterControl(input, true)
+	default: // PolicyStrict
+		return input
+	}
+}
+
+// replaceInvalidUTF8 replaces every invalid UTF-8 byte sequence in s with
+// U+FFFD, the Unicode replacement character.
+func replaceInvalidUTF8(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteRune(utf8.RuneError)
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// filterControl handles bytes below U+0020. Tab, newline, and carriage
+// return are always preserved: outside a JSON string they are legal
+// insignificant whitespace and are left untouched, while inside a JSON
+// string a raw one is invalid and is rewritten as its short JSON escape
+// ("\t", "\n", "\r"). Every other control byte is considered unwanted: if
+// escape is true it is rewritten as "\u00XX", otherwise it is dropped.
+// String boundaries are tracked with a simple quote/backslash scan; this
+// assumes the input is otherwise well-formed enough for that scan to track
+// string boundaries correctly, which holds for the malformed-but-mostly-
+// valid inputs this policy is meant to rescue.
+func filterControl(s string, escape bool) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	const hex = "0123456789abcdef"
+	inString := false
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		} else if c == '"' {
+			inString = true
+		}
+
+		if c >= 0x20 {
+			b.WriteByte(c)
+			continue
+		}
+
+		if c == '\t' || c == '\n' || c == '\r' {
+			if !inString {
+				b.WriteByte(c)
+			} else {
+				switch c {
+				case '\t':
+					b.WriteString(`\t`)
+				case '\n':
+					b.WriteString(`\n`)
+				case '\r':
+					b.WriteString(`\r`)
+				}
+			}
+			continue
+		}
+
+		if !escape {
+			continue
+		}
+		b.WriteString(`\u00`)
+		b.WriteByte(hex[(c>>4)&0xf])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONFromValuePolicy_Strict(t *testing.T) {
+	if _, err := JSONFromValuePolicy("\"a\x01b\"", PolicyStrict); err == nil {
+		t.Error("JSONFromValuePolicy(PolicyStrict) with a raw control character: want error, got nil")
+	}
+}
+
+func TestJSONFromValuePolicy_StripControl(t *testing.T) {
+	out, err := JSONFromValuePolicy("\"a\x01b\"", PolicyStripControl)
+	if err != nil {
+		t.Fatalf("JSONFromValuePolicy(PolicyStripControl): %v", err)
+	}
+	if got, want := out.String(), `"ab"`; got != want {
+		t.Errorf("JSONFromValuePolicy(PolicyStripControl) = %q, want %q", got, want)
+	}
+}
+
+func TestJSONFromValuePolicy_EscapeControl(t *testing.T) {
+	out, err := JSONFromValuePolicy("\"a\x01b\"", PolicyEscapeControl)
+	if err != nil {
+		t.Fatalf("JSONFromValuePolicy(PolicyEscapeControl): %v", err)
+	}
+	if got, want := out.String(), `"a\u0001b"`; got != want {
+		t.Errorf("JSONFromValuePolicy(PolicyEscapeControl) = %q, want %q", got, want)
+	}
+}
+
+func TestJSONFromValuePolicy_ReplaceInvalid(t *testing.T) {
+	invalid := "\"a" + string([]byte{0xff}) + "b\""
+	out, err := JSONFromValuePolicy(invalid, PolicyReplaceInvalid)
+	if err != nil {
+		t.Fatalf("JSONFromValuePolicy(PolicyReplaceInvalid): %v", err)
+	}
+	if !strings.Contains(out.String(), "�") {
+		t.Errorf("JSONFromValuePolicy(PolicyReplaceInvalid) = %q, want it to contain U+FFFD", out.String())
+	}
+}
+
+func TestJSONFromValuePolicy_PreservesAllowedWhitespace(t *testing.T) {
+	out, err := JSONFromValuePolicy("\"a\tb\nc\rd\"", PolicyStripControl)
+	if err != nil {
+		t.Fatalf("JSONFromValuePolicy(PolicyStripControl): %v", err)
+	}
+	if got, want := out.String(), `"a\tb\nc\rd"`; got != want {
+		t.Errorf("JSONFromValuePolicy(PolicyStripControl) = %q, want %q (tab/newline/CR preserved)", got, want)
+	}
+}
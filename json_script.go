@@ -0,0 +1,88 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ScriptFromJSON returns a Script which assigns j to the JavaScript
+// identifier name, e.g. for embedding server-rendered state as
+//
+//	<script>{{.}}</script>
+//
+// where {{.}} is the result of ScriptFromJSON("window.__INITIAL_STATE__", j).
+// The serialized form of j is rewritten so that it cannot prematurely close
+// the enclosing <script> element; see scriptSafeJSON.
+func ScriptFromJSON(name string, j JSON) Script {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteString("=")
+	b.WriteString(scriptSafeJSON(j.String()))
+	b.WriteString(";")
+	return Script{str: b.String()}
+}
+
+// JSONForScriptBlock returns a Script containing the serialized form of j,
+// rewritten so that it is safe to place verbatim inside any <script>
+// element's text content, such as
+//
+//	<script type="application/json">{{.}}</script>
+func JSONForScriptBlock(j JSON) Script {
+	return Script{str: scriptSafeJSON(j.String())}
+}
+
+// scriptEscapePrefixes lists the byte sequences that the HTML5 script data
+// state treats specially. Matching against the ASCII letters involved is
+// case-insensitive; only the leading '<' is actually unsafe, so
+// matchScriptEscape rewrites just that '<' to its \u-escape and copies the
+// rest of the matched text through verbatim, preserving its original case.
+var scriptEscapePrefixes = []string{"</script", "<!--", "<script"}
+
+// lineTerminatorEscapes maps the JavaScript line terminators U+2028 and
+// U+2029 to their \u-escaped replacements.
+var lineTerminatorEscapes = map[rune]string{
+	' ': `\u2028`,
+	' ': `\u2029`,
+}
+
+// scriptSafeJSON rewrites s so that none of "</script", "<!--", "<script"
+// (case-insensitive, per the HTML5 script data state rules) nor the
+// JavaScript line terminators U+2028/U+2029 can appear in it. s is assumed
+// to already be valid JSON produced by this package; everything other than
+// the offending sequences is passed through unchanged.
+func scriptSafeJSON(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); {
+		if esc, n, ok := matchScriptEscape(s[i:]); ok {
+			b.WriteString(esc)
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if esc, ok := lineTerminatorEscapes[r]; ok {
+			b.WriteString(esc)
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+func matchScriptEscape(s string) (escaped string, n int, ok bool) {
+	for _, prefix := range scriptEscapePrefixes {
+		if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+			return `\u003c` + s[1:len(prefix)], len(prefix), true
+		}
+	}
+	return "", 0, false
+}
+
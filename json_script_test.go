@@ -0,0 +1,87 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScriptFromJSON(t *testing.T) {
+	j, err := JSONFromValue(`{"a":1}`)
+	if err != nil {
+		t.Fatalf("JSONFromValue: %v", err)
+	}
+	script := ScriptFromJSON("window.__INITIAL_STATE__", j)
+	if got, want := script.String(), `window.__INITIAL_STATE__={"a":1};`; got != want {
+		t.Errorf("ScriptFromJSON = %q, want %q", got, want)
+	}
+}
+
+func TestJSONForScriptBlock_PreservesCase(t *testing.T) {
+	j, err := JSONFromValue(`"</SCRIPT and <!-- and <ScRiPt"`)
+	if err != nil {
+		t.Fatalf("JSONFromValue: %v", err)
+	}
+	got := JSONForScriptBlock(j).String()
+
+	if containsScriptBreakingSequence(got) {
+		t.Fatalf("JSONForScriptBlock output still breaks out of a <script> element: %q", got)
+	}
+	// Only the leading '<' should have been rewritten; the rest of the
+	// matched text must keep its original casing.
+	for _, want := range []string{"SCRIPT", "ScRiPt"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("JSONForScriptBlock(%q) = %q, want it to preserve casing %q", j.String(), got, want)
+		}
+	}
+}
+
+func TestJSONForScriptBlock_EscapesLineTerminators(t *testing.T) {
+	j, err := JSONFromValueWithOptions("\"a b c\"", JSONEscapeOptions{})
+	if err != nil {
+		t.Fatalf("JSONFromValueWithOptions: %v", err)
+	}
+	got := JSONForScriptBlock(j).String()
+	if strings.ContainsRune(got, ' ') || strings.ContainsRune(got, ' ') {
+		t.Errorf("JSONForScriptBlock(%q) = %q, want U+2028/U+2029 escaped", j.String(), got)
+	}
+	if !strings.Contains(got, `\u2028`) || !strings.Contains(got, `\u2029`) {
+		t.Errorf("JSONForScriptBlock(%q) = %q, want \\u2028 and \\u2029 escapes", j.String(), got)
+	}
+}
+
+// containsScriptBreakingSequence reports whether s contains a sequence
+// that could prematurely terminate an HTML <script> element or act as a
+// JavaScript line terminator inside a string literal.
+func containsScriptBreakingSequence(s string) bool {
+	lower := strings.ToLower(s)
+	for _, needle := range []string{"</script", "<!--", "<script"} {
+		if strings.Contains(lower, needle) {
+			return true
+		}
+	}
+	return strings.ContainsRune(s, ' ') || strings.ContainsRune(s, ' ')
+}
+
+func FuzzJSONForScriptBlock(f *testing.F) {
+	f.Add(`"</script><!--<script"`)
+	f.Add(`"</SCRIPT><!--<ScRiPt"`)
+	f.Add(`{"a":"` + "  " + `"}`)
+	f.Add(`[1,2,3]`)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		j, err := JSONFromValue(s)
+		if err != nil {
+			t.Skip()
+		}
+		got := JSONForScriptBlock(j).String()
+		if containsScriptBreakingSequence(got) {
+			t.Fatalf("JSONForScriptBlock(%q) = %q, prematurely terminates the script element", s, got)
+		}
+	})
+}
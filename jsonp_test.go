@@ -0,0 +1,83 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safehtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONFromValueJSONP(t *testing.T) {
+	payload, err := JSONFromValue(`{"a":1}`)
+	if err != nil {
+		t.Fatalf("JSONFromValue: %v", err)
+	}
+	p, err := JSONFromValueJSONP("myCallback", payload)
+	if err != nil {
+		t.Fatalf("JSONFromValueJSONP: %v", err)
+	}
+	want := `/**/typeof myCallback==='function'&&myCallback({"a":1});`
+	if got := p.String(); got != want {
+		t.Errorf("JSONFromValueJSONP = %q, want %q", got, want)
+	}
+	if got, want := p.ContentType(), "application/javascript; charset=utf-8"; got != want {
+		t.Errorf("ContentType() = %q, want %q", got, want)
+	}
+}
+
+func TestJSONFromValueJSONP_AllowsDottedNamespace(t *testing.T) {
+	payload, _ := JSONFromValue(`1`)
+	p, err := JSONFromValueJSONP("ns.fn", payload)
+	if err != nil {
+		t.Errorf("JSONFromValueJSONP(\"ns.fn\", ...): %v", err)
+	}
+	// The namespace root must also be guarded: typeof ns.fn throws a
+	// ReferenceError if ns itself was never declared.
+	want := `/**/typeof ns!=='undefined'&&typeof ns.fn==='function'&&ns.fn(1);`
+	if got := p.String(); got != want {
+		t.Errorf("JSONFromValueJSONP(\"ns.fn\", ...) = %q, want %q", got, want)
+	}
+}
+
+func TestJSONFromValueJSONP_RejectsInvalidCallback(t *testing.T) {
+	payload, _ := JSONFromValue(`1`)
+	for _, callback := range []string{
+		"foo;bar",
+		"1abc",
+		"foo(bar)",
+		"foo.",
+		"foo..bar",
+		"",
+	} {
+		if _, err := JSONFromValueJSONP(callback, payload); err == nil {
+			t.Errorf("JSONFromValueJSONP(%q, ...): want error, got nil", callback)
+		}
+	}
+}
+
+func TestJSONFromValueJSONP_EscapesWireSafePayload(t *testing.T) {
+	// A JSON value built with EscapeHTML:false may legally contain a raw
+	// U+2028, which is invalid inside a JS string literal if not escaped.
+	payload, err := JSONFromValueWithOptions("\"a b\"", JSONEscapeOptions{EscapeHTML: false})
+	if err != nil {
+		t.Fatalf("JSONFromValueWithOptions: %v", err)
+	}
+	if !strings.ContainsRune(payload.String(), ' ') {
+		t.Fatalf("test setup: expected payload to contain a raw U+2028, got %q", payload.String())
+	}
+
+	p, err := JSONFromValueJSONP("cb", payload)
+	if err != nil {
+		t.Fatalf("JSONFromValueJSONP: %v", err)
+	}
+	if strings.ContainsRune(p.String(), ' ') {
+		t.Errorf("JSONFromValueJSONP output still contains a raw U+2028: %q", p.String())
+	}
+	if !strings.Contains(p.String(), `\u2028`) {
+		t.Errorf("JSONFromValueJSONP output = %q, want it to contain the \\u2028 escape", p.String())
+	}
+}
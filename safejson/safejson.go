@@ -0,0 +1,118 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+// Package safejson produces safehtml.JSON values directly from Go values,
+// without ever exposing a raw []byte that could accidentally downgrade the
+// result to unescaped JSON.
+package safejson
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mikeschinkel/safehtml"
+)
+
+// Marshaler is implemented by any value that knows how to marshal itself to
+// a safehtml.JSON. Types that implement Marshaler are composed by Marshal
+// without ever round-tripping through a raw []byte.
+type Marshaler interface {
+	MarshalSafeJSON() (safehtml.JSON, error)
+}
+
+// Marshal returns the safehtml.JSON encoding of v.
+//
+// If v implements Marshaler, its MarshalSafeJSON method is used. Otherwise,
+// if v is a struct (or pointer to struct), Marshal walks its fields,
+// honoring a `safejson:"name,omitempty"` tag with the same syntax as the
+// standard library's `json` tag; a field tagged `safejson:"-"` is skipped.
+// Each field value is encoded by calling Marshal on it, so nested structs
+// are walked the same way. Values that neither implement Marshaler nor are
+// structs are encoded directly via safehtml.JSONFromMarshaler.
+func Marshal(v any) (safehtml.JSON, error) {
+	if m, ok := v.(Marshaler); ok {
+		return m.MarshalSafeJSON()
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return safehtml.JSONFromValue("null")
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return safehtml.JSONFromMarshaler(v)
+	}
+
+	// Each field's JSON is already valid and HTML-safe, so the object is
+	// assembled by concatenating the already-escaped fragments with
+	// JSONConcat rather than looping the whole thing back through the
+	// decoder/encoder - otherwise every level of struct nesting would
+	// re-tokenize everything beneath it, compounding the exact
+	// "re-parses and re-serializes" cost safejson exists to avoid.
+	fragments := []safehtml.JSON{safehtml.JSONEscaped("{")}
+	first := true
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		name, omitempty, skip := parseTag(field)
+		if skip {
+			continue
+		}
+		fv := rv.Field(i)
+		if omitempty && fv.IsZero() {
+			continue
+		}
+
+		fieldJSON, err := Marshal(fv.Interface())
+		if err != nil {
+			return safehtml.JSON{}, fmt.Errorf("safejson: field %s: %w", field.Name, err)
+		}
+		keyJSON, err := safehtml.JSONFromMarshaler(name)
+		if err != nil {
+			return safehtml.JSON{}, err
+		}
+
+		if !first {
+			fragments = append(fragments, safehtml.JSONEscaped(","))
+		}
+		first = false
+		fragments = append(fragments, keyJSON, safehtml.JSONEscaped(":"), fieldJSON)
+	}
+	fragments = append(fragments, safehtml.JSONEscaped("}"))
+
+	return safehtml.JSONConcat(fragments...), nil
+}
+
+// parseTag extracts the effective field name, the omitempty flag, and
+// whether the field should be skipped entirely from a `safejson` struct tag.
+func parseTag(field reflect.StructField) (name string, omitempty, skip bool) {
+	name = field.Name
+	tag, ok := field.Tag.Lookup("safejson")
+	if !ok {
+		return name, false, false
+	}
+	if tag == "-" {
+		return name, false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		name = parts[0]
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2017 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package safejson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mikeschinkel/safehtml"
+)
+
+type person struct {
+	Name   string `safejson:"name"`
+	Age    int    `safejson:"age,omitempty"`
+	secret string
+}
+
+func TestMarshal_Struct(t *testing.T) {
+	out, err := Marshal(person{Name: "Ada"})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if got, want := out.String(), `{"name":"Ada"}`; got != want {
+		t.Errorf("Marshal(omitempty zero field) = %q, want %q", got, want)
+	}
+
+	out, err = Marshal(person{Name: "Ada", Age: 36})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if got, want := out.String(), `{"name":"Ada","age":36}`; got != want {
+		t.Errorf("Marshal = %q, want %q", got, want)
+	}
+}
+
+func TestMarshal_NilPointer(t *testing.T) {
+	var p *person
+	out, err := Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if got, want := out.String(), "null"; got != want {
+		t.Errorf("Marshal(nil pointer) = %q, want %q", got, want)
+	}
+}
+
+func TestMarshal_EscapesHTML(t *testing.T) {
+	out, err := Marshal(person{Name: "<script>"})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if strings.Contains(out.String(), "<script>") {
+		t.Errorf("Marshal should HTML-escape field values, got %q", out.String())
+	}
+}
+
+type greeting struct {
+	Who string
+}
+
+func (g greeting) MarshalSafeJSON() (safehtml.JSON, error) {
+	return safehtml.JSONFromValue(`{"greeting":"hi ` + g.Who + `"}`)
+}
+
+func TestMarshal_UsesMarshalerInterface(t *testing.T) {
+	out, err := Marshal(greeting{Who: "Ada"})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if got, want := out.String(), `{"greeting":"hi Ada"}`; got != want {
+		t.Errorf("Marshal(Marshaler) = %q, want %q", got, want)
+	}
+}